@@ -0,0 +1,190 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrAsyncFSMStopped is returned by Fire and FireSync once Stop has been
+// called on the AsyncFSM.
+var ErrAsyncFSMStopped = errors.New("fsm: AsyncFSM has been stopped")
+
+// ContextState is implemented by States that want the context.Context passed
+// to AsyncFSM.Fire or FireSync, rather than always seeing
+// context.Background(). Actions implements ContextState via TriggerCtx.
+type ContextState interface {
+	TriggerCtx(ctx context.Context, self interface{}, action string, args []interface{}) (State, error)
+}
+
+// asyncQueueSize bounds how many Fire/FireSync calls can be accepted ahead of
+// the goroutine that runs them.
+const asyncQueueSize = 64
+
+// AsyncFSM is an FSM whose current state is owned by a single goroutine.
+// Events submitted with Fire or FireSync are serialized through a channel, so
+// handlers never run concurrently with each other.
+type AsyncFSM struct {
+	self    interface{}
+	current State
+
+	reqs chan asyncRequest
+
+	mu       sync.Mutex // guards stopped against concurrent Fire/FireSync/Stop
+	stopped  bool
+	inflight sync.WaitGroup // counts Fire/FireSync calls that have passed the stopped check and may still be sending on reqs
+
+	stopOnce sync.Once
+	loopDone chan struct{}
+}
+
+type asyncRequest struct {
+	ctx    context.Context
+	action string
+	args   []interface{}
+	result chan asyncResult // nil for Fire, which doesn't wait for the result
+}
+
+type asyncResult struct {
+	state State
+	err   error
+}
+
+// NewAsync returns a new AsyncFSM with the given "self" value and initial
+// State, and starts the goroutine that owns it.
+func NewAsync(self interface{}, initial State) *AsyncFSM {
+	initial.Enter(self)
+	a := &AsyncFSM{
+		self:     self,
+		current:  initial,
+		reqs:     make(chan asyncRequest, asyncQueueSize),
+		loopDone: make(chan struct{}),
+	}
+	go a.loop()
+	return a
+}
+
+func (a *AsyncFSM) loop() {
+	defer close(a.loopDone)
+	for req := range a.reqs {
+		state, err := a.trigger(req.ctx, req.action, req.args)
+		if req.result != nil {
+			req.result <- asyncResult{state, err}
+		}
+	}
+}
+
+func (a *AsyncFSM) trigger(ctx context.Context, action string, args []interface{}) (state State, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = panicError(r)
+		}
+	}()
+
+	var newstate State
+	if cs, ok := a.current.(ContextState); ok {
+		newstate, err = cs.TriggerCtx(ctx, a.self, action, args)
+	} else {
+		newstate, err = a.current.Trigger(a.self, action, args)
+	}
+	if err == nil && newstate != nil {
+		a.current.Exit(a.self)
+		a.current = newstate
+		a.current.Enter(a.self)
+	}
+	return a.current, err
+}
+
+// beginSend reports whether the caller may go on to send on a.reqs. If it
+// returns true, the caller must follow up with exactly one a.inflight.Done(),
+// whether or not it actually sends, so Stop can tell when it's safe to close
+// a.reqs without racing a send.
+func (a *AsyncFSM) beginSend() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.stopped {
+		return false
+	}
+	a.inflight.Add(1)
+	return true
+}
+
+// Fire submits an action to be run on the AsyncFSM's goroutine and returns
+// once it has been accepted, without waiting for it to run. It returns an
+// error if ctx is done, or if the AsyncFSM has been stopped, before the
+// action could be accepted. Once accepted, Stop guarantees the action will
+// run. Use FireSync to wait for the result.
+func (a *AsyncFSM) Fire(ctx context.Context, action string, args ...interface{}) error {
+	if !a.beginSend() {
+		return ErrAsyncFSMStopped
+	}
+	defer a.inflight.Done()
+
+	select {
+	case a.reqs <- asyncRequest{ctx: ctx, action: action, args: args}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// FireSync submits an action and blocks until it has run, returning the
+// resulting current state and any error from the handler. It returns early
+// with ctx's error if ctx is done before the action runs.
+func (a *AsyncFSM) FireSync(ctx context.Context, action string, args ...interface{}) (State, error) {
+	if !a.beginSend() {
+		return nil, ErrAsyncFSMStopped
+	}
+	result := make(chan asyncResult, 1)
+	accepted := a.send(ctx, asyncRequest{ctx: ctx, action: action, args: args, result: result})
+	if !accepted {
+		return nil, ctx.Err()
+	}
+
+	select {
+	case res := <-result:
+		return res.state, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// send performs the actual channel send for a request that already passed
+// beginSend, always releasing a.inflight exactly once.
+func (a *AsyncFSM) send(ctx context.Context, req asyncRequest) bool {
+	defer a.inflight.Done()
+	select {
+	case a.reqs <- req:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Stop shuts the AsyncFSM down: no further actions are accepted by Fire or
+// FireSync after Stop returns, but any action already accepted is run to
+// completion before the goroutine exits. Stop blocks until that draining is
+// done or ctx is done. It is safe to call Stop more than once.
+func (a *AsyncFSM) Stop(ctx context.Context) error {
+	a.stopOnce.Do(func() {
+		a.mu.Lock()
+		a.stopped = true
+		a.mu.Unlock()
+
+		// Every Fire/FireSync that got past beginSend before stopped was set
+		// either already sent on reqs or is about to; once they've all called
+		// inflight.Done(), nothing else can send, so closing reqs is safe and
+		// the loop will run everything already queued before it exits. The
+		// wait runs on its own goroutine so that Stop still honours ctx.
+		go func() {
+			a.inflight.Wait()
+			close(a.reqs)
+		}()
+	})
+	select {
+	case <-a.loopDone:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
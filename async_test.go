@@ -0,0 +1,188 @@
+package fsm
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingState appends every triggered action to a shared, mutex-guarded
+// slice, so tests can assert on the order AsyncFSM actually ran them in.
+type recordingState struct {
+	mu  *sync.Mutex
+	ran *[]string
+}
+
+func newRecordingState() recordingState {
+	return recordingState{mu: &sync.Mutex{}, ran: &[]string{}}
+}
+
+func (s recordingState) Trigger(self interface{}, action string, args []interface{}) (State, error) {
+	s.mu.Lock()
+	*s.ran = append(*s.ran, action)
+	s.mu.Unlock()
+	return s, nil
+}
+func (recordingState) Enter(self interface{}) {}
+func (recordingState) Exit(self interface{})  {}
+
+func (s recordingState) actions() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]string, len(*s.ran))
+	copy(out, *s.ran)
+	return out
+}
+
+func TestAsyncFSMFireSyncOrdering(t *testing.T) {
+	s := newRecordingState()
+	a := NewAsync(nil, s)
+	defer a.Stop(context.Background())
+
+	want := []string{"a", "b", "c"}
+	for _, action := range want {
+		if _, err := a.FireSync(context.Background(), action); err != nil {
+			t.Fatalf("FireSync(%q): %v", action, err)
+		}
+	}
+
+	got := s.actions()
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestAsyncFSMFireThenStopDrains(t *testing.T) {
+	s := newRecordingState()
+	a := NewAsync(nil, s)
+
+	for i := 0; i < 5; i++ {
+		if err := a.Fire(context.Background(), "x"); err != nil {
+			t.Fatalf("Fire #%d: %v", i, err)
+		}
+	}
+	if err := a.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+
+	if got := s.actions(); len(got) != 5 {
+		t.Fatalf("got %d actions run, want 5: %v", len(got), got)
+	}
+}
+
+// blockingState blocks its first Trigger call until unblock is closed, so
+// tests can deterministically occupy the AsyncFSM's goroutine.
+type blockingState struct {
+	startOnce *sync.Once
+	started   chan struct{}
+	unblock   chan struct{}
+}
+
+func newBlockingState() blockingState {
+	return blockingState{startOnce: &sync.Once{}, started: make(chan struct{}), unblock: make(chan struct{})}
+}
+
+func (s blockingState) Trigger(self interface{}, action string, args []interface{}) (State, error) {
+	s.startOnce.Do(func() { close(s.started) })
+	<-s.unblock
+	return s, nil
+}
+func (blockingState) Enter(self interface{}) {}
+func (blockingState) Exit(self interface{})  {}
+
+func TestAsyncFSMFireCtxCancelWhenQueueFull(t *testing.T) {
+	b := newBlockingState()
+	a := NewAsync(nil, b)
+	defer func() {
+		close(b.unblock)
+		if err := a.Stop(context.Background()); err != nil {
+			t.Fatalf("Stop: %v", err)
+		}
+	}()
+
+	if err := a.Fire(context.Background(), "go"); err != nil {
+		t.Fatalf("Fire: %v", err)
+	}
+	select {
+	case <-b.started:
+	case <-time.After(time.Second):
+		t.Fatal("handler never started")
+	}
+
+	// The loop goroutine is now stuck running the first action, so these
+	// fill the buffered queue without blocking.
+	for i := 0; i < asyncQueueSize; i++ {
+		if err := a.Fire(context.Background(), "noop"); err != nil {
+			t.Fatalf("Fire #%d: %v", i, err)
+		}
+	}
+
+	// The queue is full and nothing is draining it, so this Fire can only
+	// return via ctx, not by being accepted.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := a.Fire(ctx, "noop"); err != context.Canceled {
+		t.Fatalf("Fire with cancelled ctx and full queue: got %v, want context.Canceled", err)
+	}
+}
+
+func TestAsyncFSMStopIsIdempotent(t *testing.T) {
+	a := NewAsync(nil, loopState{})
+	if err := a.Stop(context.Background()); err != nil {
+		t.Fatalf("first Stop: %v", err)
+	}
+	if err := a.Stop(context.Background()); err != nil {
+		t.Fatalf("second Stop: %v", err)
+	}
+}
+
+func TestAsyncFSMFireAfterStop(t *testing.T) {
+	a := NewAsync(nil, loopState{})
+	if err := a.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if err := a.Fire(context.Background(), "x"); err != ErrAsyncFSMStopped {
+		t.Fatalf("Fire after Stop: got %v, want ErrAsyncFSMStopped", err)
+	}
+}
+
+// TestAsyncFSMConcurrentFireAndStop fires many concurrent requests against an
+// AsyncFSM that's concurrently being stopped, and checks that every Fire call
+// reporting success actually got run: none may be accepted and then
+// stranded. Run with -race to also confirm there's no data race.
+func TestAsyncFSMConcurrentFireAndStop(t *testing.T) {
+	s := newRecordingState()
+	a := NewAsync(nil, s)
+
+	const n = 200
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	accepted := 0
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := a.Fire(context.Background(), "x"); err == nil {
+				mu.Lock()
+				accepted++
+				mu.Unlock()
+			}
+		}()
+	}
+	go a.Stop(context.Background())
+	wg.Wait()
+
+	if err := a.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+
+	if got := len(s.actions()); got != accepted {
+		t.Fatalf("got %d actions run, want %d (every accepted Fire must run)", got, accepted)
+	}
+}
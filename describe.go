@@ -0,0 +1,69 @@
+package fsm
+
+import (
+	"reflect"
+	"strings"
+)
+
+// TransitionInfo describes one action a State can handle and, where known,
+// the state it leads to.
+type TransitionInfo struct {
+	Action string
+	To     string       // best-effort name of the destination state, or "" if it can't be inferred
+	ToType reflect.Type // concrete Go type of the destination state, or nil if it can't be inferred
+}
+
+// Describable is implemented by States that can report their own outgoing
+// transitions, for use by tools such as fsm/visualize. Actions implements
+// Describable by inspecting each handler's declared return type.
+type Describable interface {
+	Describe() []TransitionInfo
+}
+
+// Describe reports the action each handler in a responds to, along with the
+// destination state's type if the handler's declared return type is a
+// concrete State implementation rather than the fsm.State interface itself.
+// Callers that have a registry of destination States (such as fsm/visualize)
+// should resolve by ToType, since ToType identifies the destination exactly;
+// To is only the type's name (with any pointer indirection stripped), which
+// may not match how a State is keyed elsewhere.
+//
+// The destination type is captured once, the first time a handler is either
+// triggered or described, whichever comes first, so calling Describe after
+// the FSM has already run still reports the same destination it would have
+// before.
+func (a Actions) Describe() []TransitionInfo {
+	infos := make([]TransitionInfo, 0, len(a))
+	for name := range a {
+		if isReservedAction(name) || strings.HasSuffix(name, "?") {
+			continue
+		}
+		if handler := a.getActionHandler(name); handler != nil {
+			infos = append(infos, TransitionInfo{Action: name, To: handler.toName, ToType: handler.toType})
+		}
+	}
+	return infos
+}
+
+// destinationInfo inspects a handler function's declared return type and
+// reports the destination state's unqualified type name (with any pointer
+// indirection stripped) and its reflect.Type, or ("", nil) if the handler
+// doesn't declare a concrete State return type.
+func destinationInfo(typ reflect.Type) (name string, destType reflect.Type) {
+	if typ.NumOut() == 0 {
+		return "", nil
+	}
+	out := typ.Out(0)
+	if out.Kind() == reflect.Interface {
+		return "", nil
+	}
+	if !out.AssignableTo(reflect.TypeOf((*State)(nil)).Elem()) {
+		return "", nil
+	}
+
+	named := out
+	for named.Kind() == reflect.Ptr {
+		named = named.Elem()
+	}
+	return named.Name(), out
+}
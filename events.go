@@ -0,0 +1,119 @@
+package fsm
+
+import (
+	"sync"
+	"time"
+)
+
+// An Event describes a single call to Trigger, successful or not. It is
+// delivered to every subscriber registered with Subscribe.
+type Event struct {
+	Action string        // the action passed to Trigger
+	Args   []interface{} // the args passed to Trigger
+	From   State         // the state Trigger was called on
+	To     State         // the resulting state; equal to From if no transition occurred
+	Err    error         // non-nil if the handler returned an error or Trigger panicked
+	At     time.Time     // when the trigger completed
+}
+
+type subscription struct {
+	fn    func(Event)
+	queue chan Event
+
+	// closeMu guards queue against a send in deliver racing a close in
+	// closeQueue; both must treat "closed" as final and atomic with the
+	// channel operation, or deliver can send on an already-closed queue.
+	closeMu sync.Mutex
+	closed  bool
+}
+
+// deliver runs fn directly, or for an async subscription, enqueues ev for the
+// subscription's own goroutine to run. For an async subscription it is a
+// no-op once the subscription has been unsubscribed; a synchronous
+// subscription's fn is called regardless.
+func (sub *subscription) deliver(ev Event) {
+	if sub.queue == nil {
+		sub.fn(ev)
+		return
+	}
+	sub.closeMu.Lock()
+	defer sub.closeMu.Unlock()
+	if sub.closed {
+		return
+	}
+	sub.queue <- ev
+}
+
+func (sub *subscription) closeQueue() {
+	if sub.queue == nil {
+		return
+	}
+	sub.closeMu.Lock()
+	defer sub.closeMu.Unlock()
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	close(sub.queue)
+}
+
+// Subscribe registers fn to be called synchronously, in order, after every
+// Trigger on f completes, whether it succeeded, returned an error, or
+// panicked. fn runs on the goroutine that called Trigger, so a slow or
+// blocking subscriber will delay the caller; use SubscribeAsync to avoid
+// that.
+//
+// The returned unsubscribe function removes fn; it is safe to call more than
+// once.
+func (f *FSM) Subscribe(fn func(ev Event)) (unsubscribe func()) {
+	return f.subscribe(&subscription{fn: fn})
+}
+
+// SubscribeAsync is like Subscribe, but fn is invoked on a dedicated
+// goroutine that reads from a buffered queue of size bufferSize, so a slow
+// subscriber does not block Trigger. Events for this subscriber are still
+// delivered in the order they occurred. If the queue fills up, Trigger blocks
+// until there is room.
+func (f *FSM) SubscribeAsync(bufferSize int, fn func(ev Event)) (unsubscribe func()) {
+	sub := &subscription{fn: fn, queue: make(chan Event, bufferSize)}
+	go func() {
+		for ev := range sub.queue {
+			sub.fn(ev)
+		}
+	}()
+	return f.subscribe(sub)
+}
+
+func (f *FSM) subscribe(sub *subscription) (unsubscribe func()) {
+	f.subsMu.Lock()
+	f.subscribers = append(f.subscribers, sub)
+	f.subsMu.Unlock()
+
+	var once bool
+	return func() {
+		f.subsMu.Lock()
+		defer f.subsMu.Unlock()
+		if once {
+			return
+		}
+		once = true
+		for i, s := range f.subscribers {
+			if s == sub {
+				f.subscribers = append(f.subscribers[:i], f.subscribers[i+1:]...)
+				break
+			}
+		}
+		sub.closeQueue()
+	}
+}
+
+func (f *FSM) notify(ev Event) {
+	f.subsMu.Lock()
+	subs := make([]*subscription, len(f.subscribers))
+	copy(subs, f.subscribers)
+	f.subsMu.Unlock()
+
+	for _, sub := range subs {
+		sub.deliver(ev)
+	}
+}
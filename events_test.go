@@ -0,0 +1,109 @@
+package fsm
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// loopState is a State that accepts any action and stays in place, for tests
+// that only care about what Trigger/Subscribe report, not state transitions.
+type loopState struct{}
+
+func (loopState) Trigger(self interface{}, action string, args []interface{}) (State, error) {
+	return loopState{}, nil
+}
+func (loopState) Enter(self interface{}) {}
+func (loopState) Exit(self interface{})  {}
+
+func TestSubscribeOrdering(t *testing.T) {
+	f := New(nil, loopState{})
+
+	var mu sync.Mutex
+	var seen []string
+	unsubscribe := f.Subscribe(func(ev Event) {
+		mu.Lock()
+		seen = append(seen, ev.Action)
+		mu.Unlock()
+	})
+	defer unsubscribe()
+
+	for _, action := range []string{"a", "b", "c"} {
+		if err := f.Trigger(action); err != nil {
+			t.Fatalf("Trigger(%q): %v", action, err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"a", "b", "c"}
+	if len(seen) != len(want) {
+		t.Fatalf("got %v, want %v", seen, want)
+	}
+	for i := range want {
+		if seen[i] != want[i] {
+			t.Fatalf("got %v, want %v", seen, want)
+		}
+	}
+}
+
+func TestSubscribeAsyncOrdering(t *testing.T) {
+	f := New(nil, loopState{})
+
+	var mu sync.Mutex
+	var seen []string
+	done := make(chan struct{})
+	unsubscribe := f.SubscribeAsync(4, func(ev Event) {
+		mu.Lock()
+		seen = append(seen, ev.Action)
+		n := len(seen)
+		mu.Unlock()
+		if n == 3 {
+			close(done)
+		}
+	})
+	defer unsubscribe()
+
+	for _, action := range []string{"a", "b", "c"} {
+		if err := f.Trigger(action); err != nil {
+			t.Fatalf("Trigger(%q): %v", action, err)
+		}
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for async subscriber")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"a", "b", "c"}
+	for i := range want {
+		if seen[i] != want[i] {
+			t.Fatalf("got %v, want %v", seen, want)
+		}
+	}
+}
+
+// TestUnsubscribeRaceWithAsyncDelivery exercises the race between notify
+// sending on an async subscriber's queue and a concurrent unsubscribe closing
+// it; run with -race, it must neither panic nor report a data race.
+func TestUnsubscribeRaceWithAsyncDelivery(t *testing.T) {
+	f := New(nil, loopState{})
+	unsubscribe := f.SubscribeAsync(1, func(ev Event) {})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			f.Trigger("go")
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		unsubscribe()
+	}()
+	wg.Wait()
+}
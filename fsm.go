@@ -1,12 +1,21 @@
 // Package fsm implements simple Finite-State Machines.
 package fsm
 
-import "reflect"
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
 
 // A Finite-State Machine
 type FSM struct {
 	self    interface{}
 	current State
+
+	subsMu      sync.Mutex
+	subscribers []*subscription
 }
 
 // Returns a new FSM with a given "self" value (see State) and an initial State.
@@ -21,16 +30,37 @@ func New(self interface{}, initial State) *FSM {
 // Triggers an action on an FSM. Trigger will panic if the action is unknown or
 // cannot be triggered from the current state. args will be passed to the
 // current State's Trigger method.
+//
+// Every subscriber registered with Subscribe is notified after Trigger
+// completes, whether it succeeded, returned an error, or panicked.
 func (f *FSM) Trigger(action string, args ...interface{}) (err error) {
+	from := f.current
+	defer func() {
+		if r := recover(); r != nil {
+			f.notify(Event{Action: action, Args: args, From: from, To: from, At: time.Now(), Err: panicError(r)})
+			panic(r)
+		}
+	}()
+
 	newstate, err := f.current.Trigger(f.self, action, args)
+	to := from
 	if err == nil && newstate != nil {
 		f.current.Exit(f.self)
 		f.current = newstate
 		f.current.Enter(f.self)
+		to = newstate
 	}
+	f.notify(Event{Action: action, Args: args, From: from, To: to, At: time.Now(), Err: err})
 	return
 }
 
+func panicError(r interface{}) error {
+	if err, ok := r.(error); ok {
+		return err
+	}
+	return fmt.Errorf("%v", r)
+}
+
 // State is responsible for handling triggered actions and state transitions.
 type State interface {
 
@@ -55,47 +85,74 @@ type State interface {
 //
 // Actions maps action names (as they are passed to the FSM's Trigger method)
 // to handler functions. Each handler function must accept an arbitrary value
-// for "self" as the first parameter. If there are any extra input paramaters,
-// then they must be passed when triggering the action, or else Trigger will
-// panic. Each handler function may return no values, in which case Trigger
+// for "self" as the first parameter. The second parameter may optionally be a
+// context.Context, in which case it receives the ctx passed to TriggerCtx (see
+// AsyncFSM). If there are any extra input paramaters, then they must be
+// passed when triggering the action, or else Trigger will panic. Each handler
+// function may return no values, in which case Trigger
 // will return nil (for both the State and error return values); one value,
 // which must be of a type that is assignable to type State; or one value as
 // described and an error value.
 //
 // Enter and Exit are implemented by using the special action names "_enter"
 // and "_exit", respectively, if they exist in the map.
+//
+// An action may have a guard: a handler keyed "<action>?" that returns a
+// bool. The guard is called before the action's own handler; if it returns
+// false, Trigger returns ErrGuardRejected instead of running the handler.
+// Can and AvailableActions consult guards too.
 type Actions map[string]interface{}
 
 // Triggers an action by calling the corresponding handler function. Trigger
 // will panic if there is no entry for the given action.
 func (a Actions) Trigger(self interface{}, action string, args []interface{}) (State, error) {
+	return a.TriggerCtx(context.Background(), self, action, args)
+}
+
+// TriggerCtx is like Trigger, but ctx is passed to handlers that declare a
+// context.Context as their second parameter (see ContextState). It implements
+// the fsm.ContextState interface, so AsyncFSM uses it automatically.
+//
+// If a has a guard entry for action (see Can), and the guard returns false,
+// TriggerCtx returns ErrGuardRejected instead of running the action's handler.
+func (a Actions) TriggerCtx(ctx context.Context, self interface{}, action string, args []interface{}) (State, error) {
+	if guard := a.getGuardHandler(action); guard != nil && !guard(ctx, self, args) {
+		return nil, ErrGuardRejected
+	}
+
 	handler := a.getActionHandler(action)
 	if handler == nil {
 		panic("action '" + action + "' cannot be triggered from the current state")
 	}
-	return handler(self, args)
+	return handler.call(ctx, self, args)
 }
 
 // Enter will call the function associated with the "_enter" key, if it exists.
 func (a Actions) Enter(self interface{}) {
 	if handler := a.getActionHandler("_enter"); handler != nil {
-		handler(self, nil)
+		handler.call(context.Background(), self, nil)
 	}
 }
 
 // Exit will call the function associated with the "_exit" key, if it exists.
 func (a Actions) Exit(self interface{}) {
 	if handler := a.getActionHandler("_exit"); handler != nil {
-		handler(self, nil)
+		handler.call(context.Background(), self, nil)
 	}
 }
 
-func (a Actions) getActionHandler(action string) actionHandler {
+// getActionHandler returns the memoized *actionHandler for action, building
+// and caching it in a on first access (whether that access is a Trigger or a
+// Describe). Memoizing the whole *actionHandler, not just the reflect-based
+// call closure, means the destination type captured from the user's handler
+// at construction survives in a[action] for the life of the map, instead of
+// being lost the moment the raw func value is overwritten.
+func (a Actions) getActionHandler(action string) *actionHandler {
 	value := a[action]
 	if value == nil {
 		return nil
 	}
-	handler, ok := value.(actionHandler)
+	handler, ok := value.(*actionHandler)
 	if !ok {
 		handler = newActionHandler(reflect.ValueOf(value))
 		a[action] = handler
@@ -103,9 +160,17 @@ func (a Actions) getActionHandler(action string) actionHandler {
 	return handler
 }
 
-type actionHandler func(self interface{}, args []interface{}) (State, error)
+// actionHandler is the memoized, reflect-compiled form of a user-supplied
+// handler function, plus the destination state info Describe reports.
+type actionHandler struct {
+	call   func(ctx context.Context, self interface{}, args []interface{}) (State, error)
+	toName string
+	toType reflect.Type
+}
+
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
 
-func newActionHandler(handler reflect.Value) actionHandler {
+func newActionHandler(handler reflect.Value) *actionHandler {
 	typ := handler.Type()
 
 	// Check function signature
@@ -130,19 +195,43 @@ func newActionHandler(handler reflect.Value) actionHandler {
 		panic("action handler must return at most two values")
 	}
 
-	return actionHandler(func(self interface{}, args []interface{}) (newstate State, err error) {
-		params := make([]reflect.Value, 1+len(args))
-		params[0] = reflect.ValueOf(self)
-		for i, param := range args {
-			params[i+1] = reflect.ValueOf(param)
-		}
-		results := handler.Call(params)
-		if len(results) > 0 {
-			reflect.ValueOf(&newstate).Elem().Set(results[0])
-			if len(results) > 1 {
-				reflect.ValueOf(&err).Elem().Set(results[1])
+	// A handler may optionally declare a context.Context as its second input
+	// parameter (after self), in which case it receives the ctx passed to
+	// TriggerCtx (or context.Background() if triggered via plain Trigger).
+	ctxAware := typ.NumIn() >= 2 && typ.In(1) == contextType
+	paramOffset := 1
+	if ctxAware {
+		paramOffset = 2
+	}
+
+	toName, toType := destinationInfo(typ)
+
+	return &actionHandler{
+		toName: toName,
+		toType: toType,
+		call: func(ctx context.Context, self interface{}, args []interface{}) (newstate State, err error) {
+			params := make([]reflect.Value, paramOffset+len(args))
+			params[0] = reflect.ValueOf(self)
+			if self == nil {
+				params[0] = reflect.Zero(typ.In(0))
 			}
-		}
-		return
-	})
+			if ctxAware {
+				if ctx == nil {
+					ctx = context.Background()
+				}
+				params[1] = reflect.ValueOf(ctx)
+			}
+			for i, param := range args {
+				params[paramOffset+i] = reflect.ValueOf(param)
+			}
+			results := handler.Call(params)
+			if len(results) > 0 {
+				reflect.ValueOf(&newstate).Elem().Set(results[0])
+				if len(results) > 1 {
+					reflect.ValueOf(&err).Elem().Set(results[1])
+				}
+			}
+			return
+		},
+	}
 }
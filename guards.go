@@ -0,0 +1,139 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ErrGuardRejected is returned by Trigger when the action's guard (see
+// Actions) rejects the trigger.
+var ErrGuardRejected = errors.New("fsm: action rejected by guard")
+
+// ActionProvider is implemented by States that can report which actions they
+// currently accept, for use by (*FSM).Can and (*FSM).AvailableActions.
+// Actions implements ActionProvider.
+type ActionProvider interface {
+	Can(self interface{}, action string) bool
+	AvailableActions(self interface{}) []string
+}
+
+// Can reports whether action could currently be triggered, without actually
+// triggering it. It returns false if the current state doesn't implement
+// ActionProvider, or if evaluating a guard panics (for example because the
+// guard requires trigger args, which Can does not have).
+func (f *FSM) Can(action string) bool {
+	ap, ok := f.current.(ActionProvider)
+	if !ok {
+		return false
+	}
+	return ap.Can(f.self, action)
+}
+
+// AvailableActions lists the actions that could currently be triggered. It
+// returns nil if the current state doesn't implement ActionProvider.
+func (f *FSM) AvailableActions() []string {
+	ap, ok := f.current.(ActionProvider)
+	if !ok {
+		return nil
+	}
+	return ap.AvailableActions(f.self)
+}
+
+// Can reports whether action has a handler in a and, if action has a guard
+// entry (a handler keyed "action?"), whether that guard currently returns
+// true. The guard is called with no trigger args, so a guard that requires
+// any is treated as not satisfiable by Can and is reported as false.
+func (a Actions) Can(self interface{}, action string) (can bool) {
+	if isReservedAction(action) || strings.HasSuffix(action, "?") {
+		return false
+	}
+	if _, ok := a[action]; !ok {
+		return false
+	}
+	guard := a.getGuardHandler(action)
+	if guard == nil {
+		return true
+	}
+	defer func() {
+		if recover() != nil {
+			can = false
+		}
+	}()
+	return guard(context.Background(), self, nil)
+}
+
+// AvailableActions lists the actions in a that Can reports true for.
+func (a Actions) AvailableActions(self interface{}) []string {
+	var names []string
+	for key := range a {
+		if isReservedAction(key) || strings.HasSuffix(key, "?") {
+			continue
+		}
+		if a.Can(self, key) {
+			names = append(names, key)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func isReservedAction(action string) bool {
+	return action == "_enter" || action == "_exit"
+}
+
+func (a Actions) getGuardHandler(action string) guardHandler {
+	key := action + "?"
+	value := a[key]
+	if value == nil {
+		return nil
+	}
+	handler, ok := value.(guardHandler)
+	if !ok {
+		handler = newGuardHandler(reflect.ValueOf(value))
+		a[key] = handler
+	}
+	return handler
+}
+
+type guardHandler func(ctx context.Context, self interface{}, args []interface{}) bool
+
+func newGuardHandler(handler reflect.Value) guardHandler {
+	typ := handler.Type()
+
+	if typ.Kind() != reflect.Func {
+		panic("guard handler must be a function")
+	}
+	if typ.NumIn() == 0 {
+		panic("guard handler must have at least one input parameter")
+	}
+	if typ.NumOut() != 1 || typ.Out(0).Kind() != reflect.Bool {
+		panic("guard handler must return exactly one bool")
+	}
+
+	ctxAware := typ.NumIn() >= 2 && typ.In(1) == contextType
+	paramOffset := 1
+	if ctxAware {
+		paramOffset = 2
+	}
+
+	return guardHandler(func(ctx context.Context, self interface{}, args []interface{}) bool {
+		params := make([]reflect.Value, paramOffset+len(args))
+		params[0] = reflect.ValueOf(self)
+		if self == nil {
+			params[0] = reflect.Zero(typ.In(0))
+		}
+		if ctxAware {
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			params[1] = reflect.ValueOf(ctx)
+		}
+		for i, param := range args {
+			params[paramOffset+i] = reflect.ValueOf(param)
+		}
+		return handler.Call(params)[0].Bool()
+	})
+}
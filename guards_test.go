@@ -0,0 +1,107 @@
+package fsm
+
+import "testing"
+
+func TestActionsTriggerRejectedByGuard(t *testing.T) {
+	allow := false
+	a := Actions{
+		"start?": func(self interface{}) bool { return allow },
+		"start":  func(self interface{}) State { return nil },
+	}
+	f := New(nil, a)
+
+	if err := f.Trigger("start"); err != ErrGuardRejected {
+		t.Fatalf("Trigger(start) with rejecting guard: got %v, want ErrGuardRejected", err)
+	}
+
+	allow = true
+	if err := f.Trigger("start"); err != nil {
+		t.Fatalf("Trigger(start) with accepting guard: %v", err)
+	}
+}
+
+func TestActionsCan(t *testing.T) {
+	allow := false
+	a := Actions{
+		"start?": func(self interface{}) bool { return allow },
+		"start":  func(self interface{}) State { return nil },
+		"stop":   func(self interface{}) State { return nil },
+	}
+
+	if a.Can(nil, "start") {
+		t.Fatal("Can(start) with rejecting guard: got true, want false")
+	}
+	if !a.Can(nil, "stop") {
+		t.Fatal("Can(stop) with no guard: got false, want true")
+	}
+	if a.Can(nil, "missing") {
+		t.Fatal("Can(missing): got true, want false")
+	}
+
+	allow = true
+	if !a.Can(nil, "start") {
+		t.Fatal("Can(start) with accepting guard: got false, want true")
+	}
+}
+
+func TestActionsCanPanickingGuardReportsFalse(t *testing.T) {
+	a := Actions{
+		"start?": func(self interface{}, args []interface{}) bool { return args[0].(bool) },
+		"start":  func(self interface{}) State { return nil },
+	}
+
+	// The guard requires an arg, which Can never supplies, so it panics; Can
+	// must recover and report false rather than letting the panic escape.
+	if a.Can(nil, "start") {
+		t.Fatal("Can(start) with a panicking guard: got true, want false")
+	}
+}
+
+func TestActionsAvailableActions(t *testing.T) {
+	allow := false
+	a := Actions{
+		"_enter": func(self interface{}) {},
+		"start?": func(self interface{}) bool { return allow },
+		"start":  func(self interface{}) State { return nil },
+		"stop":   func(self interface{}) State { return nil },
+	}
+
+	got := a.AvailableActions(nil)
+	want := []string{"stop"}
+	if len(got) != len(want) || got[0] != want[0] {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	allow = true
+	got = a.AvailableActions(nil)
+	want = []string{"start", "stop"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestFSMCanAndAvailableActions(t *testing.T) {
+	loop := loopState{}
+	f := New(nil, loop)
+
+	if f.Can("anything") {
+		t.Fatal("Can on a state that doesn't implement ActionProvider: got true, want false")
+	}
+	if f.AvailableActions() != nil {
+		t.Fatal("AvailableActions on a state that doesn't implement ActionProvider: got non-nil")
+	}
+
+	a := Actions{"go": func(self interface{}) State { return nil }}
+	f2 := New(nil, a)
+	if !f2.Can("go") {
+		t.Fatal("Can(go): got false, want true")
+	}
+	if got := f2.AvailableActions(); len(got) != 1 || got[0] != "go" {
+		t.Fatalf("got %v, want [go]", got)
+	}
+}
@@ -0,0 +1,209 @@
+package fsm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Named is implemented by States that have a stable name, independent of the
+// process that created them. It lets Recorder and Replay identify states by
+// name rather than by Go value, which is required to make sense of recorded
+// history after a restart.
+type Named interface {
+	Name() string
+}
+
+// StateRegistry maps state names, as returned by Named.Name, to the State
+// values Replay should recognize them as.
+type StateRegistry map[string]State
+
+// A Record is one triggered action, as recorded by a Recorder.
+type Record struct {
+	Action string
+	Args   []interface{}
+	From   string // from Named.Name(), or "" if the source state isn't Named
+	To     string // from Named.Name(), or "" if the destination state isn't Named
+	At     time.Time
+}
+
+// Store persists a sequence of Records for a Recorder, and allows Replay to
+// read them back.
+type Store interface {
+	Append(rec Record) error
+	Records() ([]Record, error)
+}
+
+// Recorder wraps an *FSM and appends a Record to a Store after every Trigger.
+type Recorder struct {
+	*FSM
+	store Store
+}
+
+// NewRecorder returns a Recorder that records every triggered action on fsm
+// to store.
+func NewRecorder(fsm *FSM, store Store) *Recorder {
+	return &Recorder{FSM: fsm, store: store}
+}
+
+// Trigger behaves like (*FSM).Trigger, and additionally appends a Record of
+// the action to the Recorder's Store. If the handler panics, Trigger panics
+// too, without recording anything.
+func (r *Recorder) Trigger(action string, args ...interface{}) error {
+	from := stateName(r.FSM.current)
+	err := r.FSM.Trigger(action, args...)
+	to := stateName(r.FSM.current)
+	if appendErr := r.store.Append(Record{Action: action, Args: args, From: from, To: to, At: time.Now()}); appendErr != nil && err == nil {
+		err = fmt.Errorf("fsm: recording action %q: %w", action, appendErr)
+	}
+	return err
+}
+
+func stateName(s State) string {
+	if n, ok := s.(Named); ok {
+		return n.Name()
+	}
+	return ""
+}
+
+// Replay reconstructs an FSM by re-triggering every Record read from store,
+// in order, starting from initial. Re-running a handler constructs its own,
+// fresh State value, which is not necessarily the same object identity as
+// the State the original run transitioned to; where a Record names a
+// destination state (from a Named state), Replay looks it up in registry by
+// name and resolves the FSM's current state to that canonical object, so
+// that code comparing the replayed FSM's state against the States in
+// registry (by identity, as in a switch over == or a map lookup) sees the
+// same values the rest of the program was built around. It is an error for
+// a named destination to be missing from registry, or for the state actually
+// reached to have a different name than the Record says it should.
+func Replay(self interface{}, initial State, registry StateRegistry, store Store) (*FSM, error) {
+	records, err := store.Records()
+	if err != nil {
+		return nil, fmt.Errorf("fsm: replay: reading records: %w", err)
+	}
+
+	f := New(self, initial)
+	for i, rec := range records {
+		if err := f.Trigger(rec.Action, rec.Args...); err != nil {
+			return nil, fmt.Errorf("fsm: replay: record %d (action %q): %w", i, rec.Action, err)
+		}
+		if rec.To == "" {
+			continue
+		}
+		canonical, ok := registry[rec.To]
+		if !ok {
+			return nil, fmt.Errorf("fsm: replay: record %d (action %q): state %q not found in registry", i, rec.Action, rec.To)
+		}
+		if got := stateName(f.current); got != rec.To {
+			return nil, fmt.Errorf("fsm: replay: record %d (action %q): expected state %q, got %q", i, rec.Action, rec.To, got)
+		}
+		f.current = canonical
+	}
+	return f, nil
+}
+
+// MemoryStore is an in-memory Store, mainly useful for tests.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records []Record
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+func (s *MemoryStore) Append(rec Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, rec)
+	return nil
+}
+
+func (s *MemoryStore) Records() ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Record, len(s.records))
+	copy(out, s.records)
+	return out, nil
+}
+
+// WriterStore appends Records as JSON to an io.Writer, one per line. It does
+// not support reading Records back; use JSONFileStore if replay is needed.
+type WriterStore struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterStore returns a WriterStore that writes to w.
+func NewWriterStore(w io.Writer) *WriterStore {
+	return &WriterStore{w: w}
+}
+
+func (s *WriterStore) Append(rec Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return json.NewEncoder(s.w).Encode(rec)
+}
+
+func (s *WriterStore) Records() ([]Record, error) {
+	return nil, errors.New("fsm: WriterStore does not support reading records back")
+}
+
+// JSONFileStore appends Records as JSON lines to a file, and can read them
+// back for Replay.
+type JSONFileStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewJSONFileStore returns a JSONFileStore backed by the file at path. The
+// file is created on the first Append if it doesn't already exist.
+func NewJSONFileStore(path string) *JSONFileStore {
+	return &JSONFileStore{path: path}
+}
+
+func (s *JSONFileStore) Append(rec Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return json.NewEncoder(f).Encode(rec)
+}
+
+func (s *JSONFileStore) Records() ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []Record
+	dec := json.NewDecoder(f)
+	for {
+		var rec Record
+		if err := dec.Decode(&rec); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
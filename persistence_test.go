@@ -0,0 +1,94 @@
+package fsm
+
+import "testing"
+
+// doorState is a Named State, each Trigger constructing a fresh value, so
+// tests can tell identity-preserved-by-registry apart from
+// identity-from-the-handler's-own-return-value.
+type doorState struct {
+	Actions
+	name string
+}
+
+func (s *doorState) Name() string { return s.name }
+
+func newOpenDoorState() *doorState {
+	s := &doorState{name: "open"}
+	s.Actions = Actions{
+		"close": func(self interface{}) *doorState { return newClosedDoorState() },
+	}
+	return s
+}
+
+func newClosedDoorState() *doorState {
+	s := &doorState{name: "closed"}
+	s.Actions = Actions{
+		"open": func(self interface{}) *doorState { return newOpenDoorState() },
+	}
+	return s
+}
+
+func TestRecorderAppendsRecords(t *testing.T) {
+	store := NewMemoryStore()
+	r := NewRecorder(New(nil, newClosedDoorState()), store)
+
+	if err := r.Trigger("open"); err != nil {
+		t.Fatalf("Trigger(open): %v", err)
+	}
+	if err := r.Trigger("close"); err != nil {
+		t.Fatalf("Trigger(close): %v", err)
+	}
+
+	records, err := store.Records()
+	if err != nil {
+		t.Fatalf("Records: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2", len(records))
+	}
+	if records[0].From != "closed" || records[0].To != "open" {
+		t.Fatalf("record 0: got From=%q To=%q, want From=closed To=open", records[0].From, records[0].To)
+	}
+	if records[1].From != "open" || records[1].To != "closed" {
+		t.Fatalf("record 1: got From=%q To=%q, want From=open To=closed", records[1].From, records[1].To)
+	}
+}
+
+func TestReplayResolvesCanonicalStates(t *testing.T) {
+	store := NewMemoryStore()
+	r := NewRecorder(New(nil, newClosedDoorState()), store)
+	if err := r.Trigger("open"); err != nil {
+		t.Fatalf("Trigger(open): %v", err)
+	}
+	if err := r.Trigger("close"); err != nil {
+		t.Fatalf("Trigger(close): %v", err)
+	}
+
+	canonicalClosed := newClosedDoorState()
+	registry := StateRegistry{
+		"open":   newOpenDoorState(),
+		"closed": canonicalClosed,
+	}
+
+	replayed, err := Replay(nil, newClosedDoorState(), registry, store)
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+
+	if replayed.current != State(canonicalClosed) {
+		t.Fatalf("Replay left current as a fresh handler-constructed value, not the registry's canonical state")
+	}
+}
+
+func TestReplayErrorsOnMissingRegistryEntry(t *testing.T) {
+	store := NewMemoryStore()
+	r := NewRecorder(New(nil, newClosedDoorState()), store)
+	if err := r.Trigger("open"); err != nil {
+		t.Fatalf("Trigger(open): %v", err)
+	}
+
+	registry := StateRegistry{} // "open" deliberately missing
+	if _, err := Replay(nil, newClosedDoorState(), registry, store); err == nil {
+		t.Fatal("expected Replay to error on a destination missing from registry")
+	}
+}
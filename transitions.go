@@ -0,0 +1,206 @@
+package fsm
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// A Transition describes a single (state, event) -> state edge for use with
+// NewFromTransitions. Guard and Action are optional.
+type Transition struct {
+	From  string // source state name, or "*" to match any state
+	To    string // destination state name
+	Event string // action name that triggers this transition
+
+	// Guard, if non-nil, must be a function accepting the FSM's self value and
+	// an []interface{} of trigger args and returning a bool. If Guard returns
+	// false, the transition does not fire and Trigger returns (nil, nil).
+	Guard interface{}
+
+	// Action, if non-nil, must be a function accepting the FSM's self value and
+	// an []interface{} of trigger args and returning an error. Action runs
+	// before the FSM switches to the destination state; a non-nil error aborts
+	// the transition.
+	Action interface{}
+}
+
+type stateEvent struct {
+	state string
+	event string
+}
+
+// NewFromTransitions returns a new FSM built from a declarative transition
+// table, as an alternative to hand-writing States with New. initial names the
+// starting state, which must appear as the From or To of some Transition.
+//
+// NewFromTransitions panics if the table is invalid: an unreachable state (one
+// that no sequence of transitions leads to from initial), a duplicate
+// (From, Event) pair, or a Guard/Action with the wrong signature.
+func NewFromTransitions(self interface{}, initial string, transitions []Transition) *FSM {
+	table := newTransitionTable(self, initial, transitions)
+	return New(self, table.states[initial])
+}
+
+type transitionTable struct {
+	self     interface{}
+	byKey    map[stateEvent]*Transition
+	wildcard map[string]*Transition
+	states   map[string]*tableState
+}
+
+func newTransitionTable(self interface{}, initial string, transitions []Transition) *transitionTable {
+	table := &transitionTable{
+		self:     self,
+		byKey:    make(map[stateEvent]*Transition),
+		wildcard: make(map[string]*Transition),
+		states:   make(map[string]*tableState),
+	}
+	table.ensureState(initial)
+
+	for i := range transitions {
+		t := &transitions[i]
+		checkGuardSignature(t.Guard)
+		checkActionSignature(t.Action)
+
+		table.ensureState(t.From)
+		table.ensureState(t.To)
+
+		if t.From == "*" {
+			if _, exists := table.wildcard[t.Event]; exists {
+				panic("fsm: duplicate wildcard transition for event '" + t.Event + "'")
+			}
+			table.wildcard[t.Event] = t
+			continue
+		}
+
+		key := stateEvent{t.From, t.Event}
+		if _, exists := table.byKey[key]; exists {
+			panic("fsm: duplicate transition for state '" + t.From + "' and event '" + t.Event + "'")
+		}
+		table.byKey[key] = t
+	}
+
+	reachable := table.reachableFrom(initial)
+	for name := range table.states {
+		if !reachable[name] {
+			panic("fsm: state '" + name + "' is unreachable from initial state '" + initial + "'")
+		}
+	}
+
+	return table
+}
+
+// reachableFrom walks the transition graph breadth-first from start,
+// following both explicit (From, Event) edges and wildcard edges (which,
+// since they apply from any state, are treated as an edge out of every
+// state already known to be reachable).
+func (table *transitionTable) reachableFrom(start string) map[string]bool {
+	visited := map[string]bool{start: true}
+	queue := []string{start}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		for _, to := range table.neighbors(name) {
+			if !visited[to] {
+				visited[to] = true
+				queue = append(queue, to)
+			}
+		}
+	}
+	return visited
+}
+
+func (table *transitionTable) neighbors(name string) []string {
+	var to []string
+	for key, t := range table.byKey {
+		if key.state == name {
+			to = append(to, t.To)
+		}
+	}
+	for _, t := range table.wildcard {
+		to = append(to, t.To)
+	}
+	return to
+}
+
+func (table *transitionTable) ensureState(name string) {
+	if name == "*" {
+		return
+	}
+	if _, ok := table.states[name]; !ok {
+		table.states[name] = &tableState{name: name, table: table}
+	}
+}
+
+func checkGuardSignature(guard interface{}) {
+	if guard == nil {
+		return
+	}
+	typ := reflect.TypeOf(guard)
+	if typ.Kind() != reflect.Func || typ.NumIn() != 2 || typ.NumOut() != 1 ||
+		typ.Out(0).Kind() != reflect.Bool {
+		panic(fmt.Sprintf("fsm: guard must be a func(self, args []interface{}) bool, got %s", typ))
+	}
+}
+
+func checkActionSignature(action interface{}) {
+	if action == nil {
+		return
+	}
+	typ := reflect.TypeOf(action)
+	if typ.Kind() != reflect.Func || typ.NumIn() != 2 || typ.NumOut() != 1 ||
+		typ.Out(0) != reflect.TypeOf((*error)(nil)).Elem() {
+		panic(fmt.Sprintf("fsm: action must be a func(self, args []interface{}) error, got %s", typ))
+	}
+}
+
+// tableState is the synthesized State implementation backing each state name
+// declared in a transition table.
+type tableState struct {
+	name  string
+	table *transitionTable
+}
+
+func (s *tableState) Trigger(self interface{}, action string, args []interface{}) (State, error) {
+	t, ok := s.table.byKey[stateEvent{s.name, action}]
+	if !ok {
+		t, ok = s.table.wildcard[action]
+	}
+	if !ok {
+		panic("action '" + action + "' cannot be triggered from the current state")
+	}
+
+	if t.Guard != nil && !callGuard(t.Guard, self, args) {
+		return nil, nil
+	}
+	if t.Action != nil {
+		if err := callAction(t.Action, self, args); err != nil {
+			return nil, err
+		}
+	}
+	return s.table.states[t.To], nil
+}
+
+func (s *tableState) Enter(self interface{}) {}
+func (s *tableState) Exit(self interface{})  {}
+
+func callGuard(guard interface{}, self interface{}, args []interface{}) bool {
+	results := reflect.ValueOf(guard).Call(callArgs(reflect.TypeOf(guard), self, args))
+	return results[0].Bool()
+}
+
+func callAction(action interface{}, self interface{}, args []interface{}) error {
+	results := reflect.ValueOf(action).Call(callArgs(reflect.TypeOf(action), self, args))
+	err, _ := results[0].Interface().(error)
+	return err
+}
+
+func callArgs(typ reflect.Type, self interface{}, args []interface{}) []reflect.Value {
+	values := make([]reflect.Value, 2)
+	values[0] = reflect.ValueOf(self)
+	if self == nil {
+		values[0] = reflect.Zero(typ.In(0))
+	}
+	values[1] = reflect.ValueOf(args)
+	return values
+}
@@ -0,0 +1,104 @@
+package fsm
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewFromTransitionsBasic(t *testing.T) {
+	f := NewFromTransitions(nil, "idle", []Transition{
+		{From: "idle", To: "running", Event: "start"},
+		{From: "running", To: "idle", Event: "stop"},
+	})
+
+	if err := f.Trigger("start"); err != nil {
+		t.Fatalf("Trigger(start): %v", err)
+	}
+	if err := f.Trigger("stop"); err != nil {
+		t.Fatalf("Trigger(stop): %v", err)
+	}
+}
+
+func TestNewFromTransitionsWildcard(t *testing.T) {
+	f := NewFromTransitions(nil, "idle", []Transition{
+		{From: "idle", To: "running", Event: "start"},
+		{From: "running", To: "error", Event: "fail"},
+		{From: "*", To: "idle", Event: "reset"},
+	})
+
+	if err := f.Trigger("start"); err != nil {
+		t.Fatalf("Trigger(start): %v", err)
+	}
+	if err := f.Trigger("fail"); err != nil {
+		t.Fatalf("Trigger(fail): %v", err)
+	}
+	if err := f.Trigger("reset"); err != nil {
+		t.Fatalf("Trigger(reset) from error: %v", err)
+	}
+}
+
+func TestNewFromTransitionsGuardRejects(t *testing.T) {
+	allow := false
+	f := NewFromTransitions(nil, "idle", []Transition{
+		{From: "idle", To: "running", Event: "start", Guard: func(self interface{}, args []interface{}) bool {
+			return allow
+		}},
+	})
+
+	if err := f.Trigger("start"); err != nil {
+		t.Fatalf("Trigger(start) with rejecting guard: %v", err)
+	}
+
+	allow = true
+	if err := f.Trigger("start"); err != nil {
+		t.Fatalf("Trigger(start) with accepting guard: %v", err)
+	}
+}
+
+func TestNewFromTransitionsActionError(t *testing.T) {
+	wantErr := errors.New("boom")
+	f := NewFromTransitions(nil, "idle", []Transition{
+		{From: "idle", To: "running", Event: "start", Action: func(self interface{}, args []interface{}) error {
+			return wantErr
+		}},
+	})
+
+	if err := f.Trigger("start"); err != wantErr {
+		t.Fatalf("Trigger(start): got %v, want %v", err, wantErr)
+	}
+}
+
+func TestNewFromTransitionsDuplicatePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic for duplicate (From, Event) pair")
+		}
+	}()
+	NewFromTransitions(nil, "idle", []Transition{
+		{From: "idle", To: "running", Event: "start"},
+		{From: "idle", To: "error", Event: "start"},
+	})
+}
+
+func TestNewFromTransitionsUnreachablePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic for unreachable state")
+		}
+	}()
+	// c and d only reference each other, never reachable from "idle".
+	NewFromTransitions(nil, "idle", []Transition{
+		{From: "idle", To: "running", Event: "start"},
+		{From: "c", To: "d", Event: "loop"},
+		{From: "d", To: "c", Event: "loop"},
+	})
+}
+
+func TestNewFromTransitionsReachableViaWildcard(t *testing.T) {
+	// "quarantined" is only reachable via the wildcard transition, which must
+	// count as reachable from every state, including "idle".
+	NewFromTransitions(nil, "idle", []Transition{
+		{From: "idle", To: "running", Event: "start"},
+		{From: "*", To: "quarantined", Event: "panic"},
+	})
+}
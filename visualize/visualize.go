@@ -0,0 +1,120 @@
+// Package visualize renders a set of fsm.States as a state diagram, for
+// documentation or debugging.
+package visualize
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/vaughan0/go-fsm"
+)
+
+// ToDOT renders states as a Graphviz digraph. Each key in states becomes a
+// node, whether or not it has any resolvable outgoing edge. Edges are
+// derived from states that implement fsm.Describable: each TransitionInfo
+// becomes an edge labelled with its action name, pointing at the entry in
+// states (the first by key order, if there are several) that holds a State of
+// the same concrete type as TransitionInfo.ToType (falling back to a states
+// entry keyed by TransitionInfo.To if no entry has that type). Transitions
+// whose destination can't be resolved to an entry in states are drawn as
+// unlabelled self-loops. States that don't implement fsm.Describable are
+// drawn as nodes with no edges; their actions are not shown.
+func ToDOT(states map[string]fsm.State) string {
+	var buf bytes.Buffer
+	buf.WriteString("digraph fsm {\n")
+	for _, name := range sortedNames(states) {
+		fmt.Fprintf(&buf, "\t%q;\n", name)
+	}
+	for _, name := range sortedNames(states) {
+		for _, edge := range edges(states, name) {
+			if edge.resolved {
+				fmt.Fprintf(&buf, "\t%q -> %q [label=%q];\n", name, edge.To, edge.Action)
+			} else {
+				fmt.Fprintf(&buf, "\t%q -> %q;\n", name, edge.To)
+			}
+		}
+	}
+	buf.WriteString("}\n")
+	return buf.String()
+}
+
+// ToMermaid renders states as a Mermaid flowchart, using the same edge
+// derivation and node-emission rules as ToDOT: every entry in states becomes
+// a node, whether or not it has any resolvable outgoing edge.
+func ToMermaid(states map[string]fsm.State) string {
+	var buf bytes.Buffer
+	buf.WriteString("graph TD\n")
+	for _, name := range sortedNames(states) {
+		fmt.Fprintf(&buf, "\t%s\n", name)
+	}
+	for _, name := range sortedNames(states) {
+		for _, edge := range edges(states, name) {
+			if edge.resolved {
+				fmt.Fprintf(&buf, "\t%s -->|%s| %s\n", name, edge.Action, edge.To)
+			} else {
+				fmt.Fprintf(&buf, "\t%s --> %s\n", name, edge.To)
+			}
+		}
+	}
+	return buf.String()
+}
+
+// resolvedEdge is a TransitionInfo annotated with whether its destination was
+// actually resolved to an entry in states, as opposed to falling back to an
+// unlabelled self-loop.
+type resolvedEdge struct {
+	fsm.TransitionInfo
+	resolved bool
+}
+
+func edges(states map[string]fsm.State, name string) []resolvedEdge {
+	d, ok := states[name].(fsm.Describable)
+	if !ok {
+		return nil
+	}
+	infos := d.Describe()
+	out := make([]resolvedEdge, len(infos))
+	for i, info := range infos {
+		out[i] = resolvedEdge{TransitionInfo: info, resolved: true}
+		if dest, ok := resolveByType(states, info.ToType); ok {
+			out[i].To = dest
+			continue
+		}
+		if info.To != "" {
+			if _, exists := states[info.To]; exists {
+				continue
+			}
+		}
+		out[i].To = name
+		out[i].resolved = false
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Action < out[j].Action })
+	return out
+}
+
+// resolveByType looks for an entry in states whose State value has the exact
+// concrete type typ, which is how TransitionInfo.ToType identifies a
+// destination regardless of what key it happens to be registered under. If
+// several entries have that type, the first in key order is returned.
+func resolveByType(states map[string]fsm.State, typ reflect.Type) (name string, ok bool) {
+	if typ == nil {
+		return "", false
+	}
+	for _, name := range sortedNames(states) {
+		if reflect.TypeOf(states[name]) == typ {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+func sortedNames(states map[string]fsm.State) []string {
+	names := make([]string, 0, len(states))
+	for name := range states {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
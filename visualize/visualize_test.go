@@ -0,0 +1,80 @@
+package visualize
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/vaughan0/go-fsm"
+)
+
+// idleState and runningState are minimal fsm.Describable states, built on
+// fsm.Actions, used to exercise ToDOT/ToMermaid's edge resolution.
+type idleState struct{ fsm.Actions }
+type runningState struct{ fsm.Actions }
+
+func newTestStates() (map[string]fsm.State, *idleState) {
+	idle := &idleState{}
+	running := &runningState{}
+	idle.Actions = fsm.Actions{
+		"start": func(self interface{}) *runningState { return running },
+	}
+	running.Actions = fsm.Actions{
+		"stop":       func(self interface{}) *idleState { return idle },
+		"go_nowhere": func(self interface{}) fsm.State { return nil },
+	}
+	return map[string]fsm.State{"idle": idle, "running": running}, idle
+}
+
+func TestToDOTResolvesEdgesByType(t *testing.T) {
+	states, _ := newTestStates()
+	dot := ToDOT(states)
+
+	for _, want := range []string{`"idle"`, `"running"`, `"idle" -> "running" [label="start"]`, `"running" -> "idle" [label="stop"]`} {
+		if !strings.Contains(dot, want) {
+			t.Fatalf("ToDOT output missing %q:\n%s", want, dot)
+		}
+	}
+	// go_nowhere declares no concrete destination type, so it must fall back
+	// to an unlabelled self-loop, not a labelled one.
+	if !strings.Contains(dot, `"running" -> "running";`) {
+		t.Fatalf("ToDOT output missing unlabelled self-loop for go_nowhere:\n%s", dot)
+	}
+	if strings.Contains(dot, `"running" -> "running" [label="go_nowhere"]`) {
+		t.Fatalf("ToDOT output should not label the go_nowhere self-loop:\n%s", dot)
+	}
+}
+
+func TestToMermaidEmitsAllNodes(t *testing.T) {
+	states, _ := newTestStates()
+	// A state with no resolvable outgoing edge must still appear as a node.
+	states["quarantine"] = &idleState{}
+
+	mermaid := ToMermaid(states)
+	for _, want := range []string{"\tidle\n", "\trunning\n", "\tquarantine\n", "idle -->|start| running", "running -->|stop| idle"} {
+		if !strings.Contains(mermaid, want) {
+			t.Fatalf("ToMermaid output missing %q:\n%s", want, mermaid)
+		}
+	}
+	if !strings.Contains(mermaid, "running --> running\n") {
+		t.Fatalf("ToMermaid output missing unlabelled self-loop for go_nowhere:\n%s", mermaid)
+	}
+}
+
+// TestDescribeSurvivesTrigger reproduces the exporter-after-Trigger bug: the
+// destination type an exporter sees must not change depending on whether the
+// FSM has already run the handler once.
+func TestDescribeSurvivesTrigger(t *testing.T) {
+	states, idle := newTestStates()
+
+	before := ToDOT(states)
+
+	f := fsm.New(nil, idle)
+	if err := f.Trigger("start"); err != nil {
+		t.Fatalf("Trigger(start): %v", err)
+	}
+
+	after := ToDOT(states)
+	if before != after {
+		t.Fatalf("ToDOT output changed after Trigger:\nbefore:\n%s\nafter:\n%s", before, after)
+	}
+}